@@ -0,0 +1,20 @@
+//go:build darwin || dragonfly || freebsd || linux || nacl || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd linux nacl netbsd openbsd solaris
+
+package gotail
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID returns the inode number backing fi, so the polling watcher can
+// tell a replaced file (rename-and-recreate rotation) apart from one
+// that was merely truncated in place, even when size and mtime coincide.
+func fileID(fi os.FileInfo) (uint64, bool) {
+	sys, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(sys.Ino), true
+}
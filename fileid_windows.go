@@ -0,0 +1,10 @@
+package gotail
+
+import "os"
+
+// fileID has no portable equivalent via os.FileInfo on Windows, so the
+// polling watcher falls back to comparing size and modification time
+// only.
+func fileID(fi os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -3,28 +3,98 @@ package gotail
 
 import (
 	"bufio"
-	"strings"
-
+	"context"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
+	"strings"
+	"sync"
 	"time"
-
-	fsnotify "gopkg.in/fsnotify.v1"
 )
 
-type Tail struct {
-	Lines chan string
+// DiscardingLogger is a ready-made Config.Logger that silences all of
+// gotail's own diagnostics.
+var DiscardingLogger = log.New(ioutil.Discard, "", 0)
 
-	reader  *bufio.Reader
-	watcher *fsnotify.Watcher
-	fname   string
-	file    *os.File
-	config  Config
+var defaultLogger = log.New(os.Stderr, "", log.LstdFlags)
+
+type Tail struct {
+	Lines chan *Line
+
+	reader *bufio.Reader
+	fw     FileWatcher
+	fname  string
+	file   *os.File
+	config Config
+
+	lineNum         int
+	offset          int64
+	rateDropped     int64
+	partialLine     bool
+	lineRateLimited bool   // true while the logical line in progress was rate limited, so its later fragments drop silently too
+	pending         []byte // bytes read past the last newline, carried to the next readLines call
+	pendingChunk    []byte // same, but for readLinesBounded's byte-at-a-time reads
+
+	log *log.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	stopOnce sync.Once
+	dead     chan struct{}
+	err      error
 }
 
 type Config struct {
 	Timeout int
+
+	// Location, if set, overrides the default "seek to end of file"
+	// behavior for an existing file. Pass in the SeekInfo from the last
+	// Line received before a restart to resume tailing where it left off.
+	Location *SeekInfo
+
+	// Poll selects the stat-based polling FileWatcher instead of the
+	// default fsnotify one. Use it on filesystems where inotify events
+	// are dropped or unavailable, e.g. NFS, FUSE, SMB, and some
+	// container bind mounts.
+	Poll bool
+
+	// Watcher, if set, overrides Poll and supplies a custom FileWatcher
+	// implementation.
+	Watcher FileWatcher
+
+	// RateLimiter, if set, caps how many lines per LeakInterval are
+	// forwarded to Lines, protecting a slow consumer from a burst.
+	// Excess lines are dropped and reported via a single synthetic Line
+	// carrying ErrRateLimited once the burst subsides.
+	RateLimiter *RateLimiter
+
+	// Context, if set, is the parent context for the Tail's watcher
+	// goroutine; cancelling it stops that goroutine, its FileWatcher and
+	// the RateLimiter leak ticker. It does not close the file handle or
+	// the Dead channel; Close does. When set, it takes precedence over
+	// the ctx argument of NewTailContext.
+	Context context.Context
+
+	// Logger receives gotail's own diagnostics (e.g. watcher warnings).
+	// It defaults to a logger writing to stderr; set it to
+	// DiscardingLogger to silence them.
+	Logger *log.Logger
+
+	// MaxLineSize, if non-zero, bounds how many bytes readLines buffers
+	// before emitting a line. A raw line longer than MaxLineSize is
+	// delivered as several Lines, each at most MaxLineSize bytes of
+	// Text, with Partial true on all but the last.
+	MaxLineSize int
+
+	// skipRateLimiterLeak keeps NewTailContext from starting its own
+	// leak goroutine for RateLimiter even when one is set. MultiTail
+	// sets this on each per-file Config when RateLimiter is shared
+	// across every matched file, so the shared bucket leaks once per
+	// LeakInterval overall instead of once per matched file.
+	skipRateLimiterLeak bool
 }
 
 // NewTail creates a new Tail Object.  During initialization, it checks to see
@@ -32,10 +102,39 @@ type Config struct {
 // before returning an error.  If the file exists, then NewTail attaches an open file handle
 // and a watcher to the file for new notifications.
 func NewTail(fname string, config Config) (*Tail, error) {
+	return NewTailContext(context.Background(), fname, config)
+}
+
+// NewTailContext is like NewTail, but ctx (or Config.Context, if set)
+// governs the Tail's goroutines: cancelling it stops the watcher
+// goroutine and FileWatcher as Close does, but leaves the file handle
+// open and Dead unclosed until Close is called.
+func NewTailContext(ctx context.Context, fname string, config Config) (*Tail, error) {
+	parent := ctx
+	if config.Context != nil {
+		parent = config.Context
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = defaultLogger
+	}
+
+	tailCtx, cancel := context.WithCancel(parent)
+
 	tail := &Tail{
-		Lines:  make(chan string),
+		Lines:  make(chan *Line),
 		fname:  fname,
 		config: config,
+		fw:     newFileWatcher(fname, config, logger),
+		log:    logger,
+		ctx:    tailCtx,
+		cancel: cancel,
+		dead:   make(chan struct{}),
+	}
+
+	if config.RateLimiter != nil && !config.skipRateLimiterLeak {
+		tail.startRateLimiterLeak()
 	}
 
 	err := tail.openAndWatch()
@@ -47,65 +146,101 @@ func NewTail(fname string, config Config) (*Tail, error) {
 	return tail, nil
 }
 
-// Close closes the tail object when finished, closing the file handle and watcher
+// startRateLimiterLeak leaks one unit from Config.RateLimiter every
+// LeakInterval until the Tail is closed.
+func (t *Tail) startRateLimiterLeak() {
+	ticker := time.NewTicker(t.config.RateLimiter.LeakInterval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				t.config.RateLimiter.leak()
+			case <-t.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Close closes the tail object when finished, closing the file handle and
+// stopping the watcher goroutine.
 func (t *Tail) Close() {
+	t.stop(nil)
+	t.cancel()
 	t.file.Close()
-	if t.watcher != nil {
-		t.watcher.Close()
-	}
 }
 
-// openAndWatch continually polls the target file to try to set an open file handler and watcher.
-// If the timeout is reached, it sends the error back to the timeout signal
-// and the function returns an error.  If no error is detected, it returns immediately.
-func (t *Tail) openAndWatch() error {
-	var err error
-	var newFile bool
+// Dead returns a channel that's closed once the Tail has stopped,
+// whether via Close or a terminal error.
+func (t *Tail) Dead() <-chan struct{} {
+	return t.dead
+}
 
-	timeout := make(chan error, 1)
+// Wait blocks until the Tail stops, then returns the terminal error that
+// stopped it, or nil if it was stopped via Close.
+func (t *Tail) Wait() error {
+	<-t.dead
+	return t.err
+}
 
-	go func() {
-		for {
-			err = t.openFile(newFile)
-			if err != nil {
-				if os.IsNotExist(err) && newFile == false {
-					newFile = true
-				}
+// stop records err (if this is the first call) and closes dead, waking
+// up any Wait callers. Safe to call more than once and from Close and a
+// terminal failure racing each other.
+func (t *Tail) stop(err error) {
+	t.stopOnce.Do(func() {
+		t.err = err
+		close(t.dead)
+	})
+}
 
-				if t.config.Timeout == 0 {
-					timeout <- err
-					break
-				} else {
-					continue
-				}
+// fail stops the Tail after a terminal error, instead of calling
+// log.Fatalln and taking the host process down with it.
+func (t *Tail) fail(err error) {
+	t.log.Println("[FATA] open and watch failed: ", err)
+	t.stop(err)
+	t.cancel()
+}
 
-			}
+// openAndWatch opens the target file and starts watching it. If the file
+// doesn't exist yet, it waits for it to appear via the configured
+// FileWatcher's BlockUntilExists — so Config.Poll and a custom
+// Config.Watcher get the same wait behavior as the fsnotify default —
+// for up to Config.Timeout seconds before giving up.
+func (t *Tail) openAndWatch() error {
+	ctx := t.ctx
+	if t.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(t.ctx, time.Duration(t.config.Timeout)*time.Second)
+		defer cancel()
+	}
 
-			err = t.watchFile(newFile)
+	newFile := false
 
-			if err == nil {
-				timeout <- nil
-				break
+	for {
+		err := t.openFile(newFile)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return err
 			}
-		}
-	}()
 
-	if t.config.Timeout != 0 {
-		go func() {
-			time.Sleep(time.Duration(t.config.Timeout) * time.Second)
+			newFile = true
 
-			timeout <- err
-		}()
-	}
+			if t.config.Timeout == 0 {
+				return err
+			}
 
-	select {
-	case err := <-timeout:
-		if err != nil {
-			return err
+			if err := t.fw.BlockUntilExists(ctx); err != nil {
+				return err
+			}
+
+			continue
 		}
-	}
 
-	return nil
+		return t.watchFile(newFile)
+	}
 }
 
 // openFile opens a file and finds the offset byte to start reading.
@@ -114,6 +249,10 @@ func (t *Tail) openAndWatch() error {
 // This is because sometimes, a new file is considered "MODIFY" and
 // file.Seek will automatically point to the last byte of the file,
 // causing it to skip the first line.
+//
+// Opening a file (whether fresh or due to rotation) resets the line
+// counter, since line numbers are only meaningful within a single open
+// of the file.
 func (t *Tail) openFile(newFile bool) (err error) {
 	if t.file != nil {
 		t.file.Close()
@@ -124,8 +263,18 @@ func (t *Tail) openFile(newFile bool) (err error) {
 		return err
 	}
 
-	if !newFile {
-		_, err = t.file.Seek(0, 2)
+	t.lineNum = 0
+	t.partialLine = false
+	t.pending = nil
+	t.pendingChunk = nil
+
+	switch {
+	case newFile:
+		t.offset = 0
+	case t.config.Location != nil:
+		t.offset, err = t.file.Seek(t.config.Location.Offset, t.config.Location.Whence)
+	default:
+		t.offset, err = t.file.Seek(0, io.SeekEnd)
 	}
 
 	if err != nil {
@@ -137,20 +286,58 @@ func (t *Tail) openFile(newFile bool) (err error) {
 	return nil
 }
 
-// watchFile assigns a new fsnotify watcher to the file if possible.
-// It it watches for any signals that lead to file change, and responds accordingly.
-func (t *Tail) watchFile(newFile bool) error {
-	if t.watcher != nil {
-		t.watcher.Close()
+// truncate handles in-place truncation (e.g. `: > file`, `cp /dev/null
+// file`, or logrotate's copytruncate) without tearing down the watcher:
+// it seeks back to the start of the file and resets the reader and line
+// counter, then carries on reading from there.
+func (t *Tail) truncate() error {
+	if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+		return err
 	}
 
-	var err error
-	t.watcher, err = fsnotify.NewWatcher()
+	t.offset = 0
+	t.lineNum = 0
+	t.partialLine = false
+	t.pending = nil
+	t.pendingChunk = nil
+	t.reader = bufio.NewReader(t.file)
+
+	return nil
+}
+
+// syncOffsetOnModify re-stats the file and truncates in place if its
+// size has fallen below what's already been read. A FileWatcher's own
+// truncation detection is a racy best-effort hint — comparing the
+// current size against the last-observed one misses a truncate that a
+// rewrite refills past the pre-truncation size before the watcher gets
+// around to re-stating it — so this authoritative check, against bytes
+// actually consumed rather than a last-observed size, runs on every
+// Modified event too, not only ones a watcher flagged as Truncated.
+func (t *Tail) syncOffsetOnModify() error {
+	fi, err := t.file.Stat()
 	if err != nil {
 		return err
 	}
 
-	err = t.watcher.Add(t.fname)
+	if fi.Size() < t.offset {
+		return t.truncate()
+	}
+
+	return nil
+}
+
+// watchFile asks the configured FileWatcher to watch the file for
+// changes, and responds to what it reports: new data is read,
+// truncation is handled in place, and deletion (including
+// rename-and-recreate rotation) triggers a full re-open so line
+// numbering and the watch restart cleanly.
+func (t *Tail) watchFile(newFile bool) error {
+	fi, err := t.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	changes, err := t.fw.ChangeEvents(t.ctx, fi)
 	if err != nil {
 		return err
 	}
@@ -162,34 +349,35 @@ func (t *Tail) watchFile(newFile bool) error {
 		}
 
 		for {
-			closed := false
 			select {
-			case evt, ok := <-t.watcher.Events:
-				// Exit if the channel is closed
+			case _, ok := <-changes.Modified:
 				if !ok {
-					closed = true
-					break
+					return
 				}
-				if evt.Op&(fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
-					if err = t.openAndWatch(); err != nil {
-						log.Fatalln("[FATA] open and watch failed: ", err)
-					}
+				if err := t.syncOffsetOnModify(); err != nil {
+					t.fail(err)
+					return
 				}
-				if evt.Op&fsnotify.Write == fsnotify.Write {
-					t.readLines()
+				t.readLines()
+			case _, ok := <-changes.Truncated:
+				if !ok {
+					return
 				}
-			case err, ok := <-t.watcher.Errors:
-				// Exit if the channel is closed
+				if err := t.truncate(); err != nil {
+					t.fail(err)
+					return
+				}
+				t.readLines()
+			case _, ok := <-changes.Deleted:
 				if !ok {
-					closed = true
-					break
+					return
 				}
-				if err != nil {
-					log.Println("[WARN] watcher error: ", err)
+				if err := t.openAndWatch(); err != nil {
+					t.fail(err)
 				}
-			}
-			if closed {
-				break
+				return
+			case <-t.ctx.Done():
+				return
 			}
 		}
 	}()
@@ -197,17 +385,197 @@ func (t *Tail) watchFile(newFile bool) error {
 	return nil
 }
 
-// readLines reads any new lines that gets added to the file.
+// sendLine delivers line to Lines, giving up if the Tail's context is
+// cancelled first. Without the ctx.Done case, a Close (or cancelled
+// parent context) racing an in-flight line would otherwise leave this
+// goroutine blocked forever on an unbuffered send nobody is left to
+// receive, leaking it along with whatever FileWatcher it holds open.
+func (t *Tail) sendLine(line *Line) bool {
+	select {
+	case t.Lines <- line:
+		return true
+	case <-t.ctx.Done():
+		return false
+	}
+}
+
+// rateLimiterCooldown backs off for the same duration RateLimiter.Wait
+// would sleep for, but stays responsive to cancellation. readLines runs
+// on the same goroutine that watchFile selects on for Modified,
+// Truncated, Deleted and ctx.Done, so a plain Wait() call here would
+// make that goroutine deaf to ctx.Done (and therefore Close) for the
+// entire backoff.
+func (t *Tail) rateLimiterCooldown() {
+	select {
+	case <-time.After(t.config.RateLimiter.cooldown()):
+	case <-t.ctx.Done():
+	}
+}
+
+// flushRateLimitNotice sends a synthetic Line reporting t.rateDropped and
+// backs off for rateLimiterCooldown, if any lines have been dropped since
+// the last flush. It reports whether the caller should go on to send the
+// line that triggered this call; false means sendLine's ctx.Done case hit
+// while delivering the notice, so the Tail is shutting down.
+func (t *Tail) flushRateLimitNotice() bool {
+	if t.rateDropped == 0 {
+		return true
+	}
+
+	dropped := t.rateDropped
+	t.rateDropped = 0
+
+	if !t.sendLine(&Line{
+		Num:  t.lineNum,
+		Time: time.Now(),
+		Err:  fmt.Errorf("%w: dropped %d lines", ErrRateLimited, dropped),
+	}) {
+		return false
+	}
+
+	t.rateLimiterCooldown()
+	return true
+}
+
+// readLines reads the next new line that has been added to the file (at
+// most one line per call; later lines wait for the next call), tracking
+// the cumulative byte offset and line number so each emitted Line can be
+// used to resume tailing later. A write that lands mid-line (no trailing
+// newline yet) leaves ReadBytes returning io.EOF with the partial bytes
+// it already consumed from the reader; those are kept in t.pending and
+// prefixed onto the next call's read so no data is lost or duplicated.
 func (t *Tail) readLines() {
 	if t.reader == nil {
 		return
 	}
 
-	line, err := t.reader.ReadString('\n')
+	if t.config.MaxLineSize > 0 {
+		t.readLinesBounded()
+		return
+	}
+
+	chunk, err := t.reader.ReadBytes('\n')
+	t.pending = append(t.pending, chunk...)
+
+	if err == io.EOF {
+		return
+	}
+
+	line := t.pending
+	t.pending = nil
+
+	t.offset += int64(len(line))
+
+	if err != nil {
+		t.sendLine(&Line{Time: time.Now(), Err: err})
+		return
+	}
+
+	t.lineNum++
+
+	if rl := t.config.RateLimiter; rl != nil && !rl.Pour(1) {
+		t.rateDropped++
+		return
+	}
+
+	if !t.flushRateLimitNotice() {
+		return
+	}
+
+	t.sendLine(&Line{
+		Text: strings.TrimRight(string(line), "\n"),
+		Num:  t.lineNum,
+		Time: time.Now(),
+		SeekInfo: SeekInfo{
+			Offset: t.offset,
+			Whence: io.SeekStart,
+		},
+	})
+}
+
+// readLinesBounded is readLines' counterpart for Config.MaxLineSize > 0:
+// it reads at most one MaxLineSize-sized fragment per call instead of a
+// whole line, so a pathologically long raw line never buffers more than
+// MaxLineSize bytes at a time.
+//
+// Config.RateLimiter is gated once per logical line rather than once per
+// fragment, same unit readLines uses: Pour is only called on the fragment
+// that starts a new line, and if that's rate limited, lineRateLimited
+// keeps every later fragment of the same line dropping silently until the
+// line ends.
+func (t *Tail) readLinesBounded() {
+	chunk, terminated, err := t.readChunk(t.config.MaxLineSize)
 
 	if err == io.EOF {
 		return
 	}
 
-	t.Lines <- strings.TrimRight(line, "\n")
+	t.offset += int64(len(chunk))
+	if terminated {
+		t.offset++ // account for the newline readChunk consumed
+	}
+
+	if err != nil {
+		t.sendLine(&Line{Time: time.Now(), Err: err})
+		return
+	}
+
+	startOfLine := !t.partialLine
+	if startOfLine {
+		t.lineNum++
+	}
+	t.partialLine = !terminated
+
+	if startOfLine {
+		if rl := t.config.RateLimiter; rl != nil && !rl.Pour(1) {
+			t.rateDropped++
+			t.lineRateLimited = true
+			return
+		}
+		t.lineRateLimited = false
+	} else if t.lineRateLimited {
+		return
+	}
+
+	if !t.flushRateLimitNotice() {
+		return
+	}
+
+	t.sendLine(&Line{
+		Text:    string(chunk),
+		Num:     t.lineNum,
+		Time:    time.Now(),
+		Partial: !terminated,
+		SeekInfo: SeekInfo{
+			Offset: t.offset,
+			Whence: io.SeekStart,
+		},
+	})
+}
+
+// readChunk reads up to maxSize bytes from t.reader, stopping early if a
+// newline is found first. It reports whether a newline (rather than the
+// size cap) ended the chunk. Bytes already accumulated from a previous
+// call that hit io.EOF before maxSize or a newline are carried in
+// t.pendingChunk and prefixed here, so a write that lands mid-fragment
+// isn't lost the way a bare early-return on io.EOF would lose it.
+func (t *Tail) readChunk(maxSize int) ([]byte, bool, error) {
+	chunk := t.pendingChunk
+	t.pendingChunk = nil
+
+	for {
+		b, err := t.reader.ReadByte()
+		if err != nil {
+			t.pendingChunk = chunk
+			return chunk, false, err
+		}
+		if b == '\n' {
+			return chunk, true, nil
+		}
+
+		chunk = append(chunk, b)
+		if len(chunk) >= maxSize {
+			return chunk, false, nil
+		}
+	}
 }
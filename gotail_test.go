@@ -1,10 +1,13 @@
 package gotail
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -60,7 +63,7 @@ func TestAppendFile(t *testing.T) {
 	assert.Equal(t, err, nil)
 	defer tail.Close()
 
-	var line string
+	var line *Line
 
 	done := make(chan bool)
 
@@ -74,13 +77,13 @@ func TestAppendFile(t *testing.T) {
 
 	<-done
 
-	assert.Equal(t, "foobar", line)
+	assert.Equal(t, "foobar", line.Text)
 
 }
 
 func TestWriteNewFile(t *testing.T) {
 	var tail *Tail
-	var line string
+	var line *Line
 	done := make(chan bool)
 
 	go func() {
@@ -100,12 +103,12 @@ func TestWriteNewFile(t *testing.T) {
 
 	<-done
 
-	assert.Equal(t, "foobar", line)
+	assert.Equal(t, "foobar", line.Text)
 }
 
 func TestRenameFile(t *testing.T) {
 	var tail *Tail
-	var line string
+	var line *Line
 	done := make(chan bool)
 
 	// Sets up background tailer
@@ -129,7 +132,7 @@ func TestRenameFile(t *testing.T) {
 
 	<-done
 
-	assert.Equal(t, "foobar", line)
+	assert.Equal(t, "foobar", line.Text)
 
 	_ = os.Remove(fname + "_new")
 	removeFile()
@@ -140,6 +143,213 @@ func TestNoFile(t *testing.T) {
 	assert.Equal(t, true, os.IsNotExist(err))
 }
 
+func TestRateLimiterTickerStopsOnClose(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	createFile("")
+	defer removeFile()
+
+	rl := &RateLimiter{Size: 10, LeakInterval: time.Millisecond}
+
+	goroutines := runtime.NumGoroutine()
+	for i := 0; i < 5; i++ {
+		tail, err := NewTail(fname, Config{Timeout: 2, RateLimiter: rl})
+		if err != nil {
+			log.Fatal(err)
+		}
+		tail.Close()
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if delta := runtime.NumGoroutine() - goroutines; delta > 2 {
+		t.Fatalf("rate limiter leak ticker did not stop on Close: %d goroutines still running", delta)
+	}
+}
+
+func TestCloseDuringWriteDoesNotLeak(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	createFile("")
+	defer removeFile()
+
+	// Closing right after a write races Close's ctx cancellation against
+	// the in-flight Modified event/Lines send it triggers.
+	goroutines := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		tail, err := NewTail(fname, Config{Timeout: 2})
+		if err != nil {
+			log.Fatal(err)
+		}
+		writeFile("line\n")
+		tail.Close()
+	}
+	time.Sleep(2 * time.Second)
+
+	if delta := runtime.NumGoroutine() - goroutines; delta > 5 {
+		t.Fatalf("Found a goroutine leak when closing during an in-flight event: %d goroutines still running", delta)
+	}
+}
+
+func TestTruncation(t *testing.T) {
+	createFile("")
+	defer removeFile()
+
+	tail, err := NewTail(fname, Config{Timeout: 10})
+	assert.Equal(t, err, nil)
+	defer tail.Close()
+
+	writeFile("first\n")
+
+	line := <-tail.Lines
+	assert.Equal(t, "first", line.Text)
+
+	f, err := os.OpenFile(fname, os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	writeContents(f, "second\n")
+	f.Close()
+
+	line = <-tail.Lines
+	assert.Equal(t, "second", line.Text)
+	assert.Equal(t, 1, line.Num)
+}
+
+func TestMaxLineSizeSplitsLongLines(t *testing.T) {
+	createFile("")
+	defer removeFile()
+
+	tail, err := NewTail(fname, Config{Timeout: 10, MaxLineSize: 4})
+	assert.Equal(t, err, nil)
+	defer tail.Close()
+
+	// "abcd" hits the MaxLineSize cap before any newline, so it's
+	// delivered as a partial fragment of the line it belongs to.
+	writeFile("abcd")
+
+	first := <-tail.Lines
+	assert.Equal(t, "abcd", first.Text)
+	assert.Equal(t, true, first.Partial)
+	assert.Equal(t, 1, first.Num)
+
+	// The rest of the same logical line arrives in a later write; since
+	// it terminates before the cap, it's the final, non-partial fragment.
+	writeFile("wx\n")
+
+	second := <-tail.Lines
+	assert.Equal(t, "wx", second.Text)
+	assert.Equal(t, false, second.Partial)
+	assert.Equal(t, 1, second.Num)
+}
+
+func TestMaxLineSizeRateLimitsBoundedLines(t *testing.T) {
+	createFile("")
+	defer removeFile()
+
+	rl := &RateLimiter{Size: 1, LeakInterval: 10 * time.Millisecond}
+
+	// skipRateLimiterLeak keeps the bucket from draining on its own, so
+	// the test controls exactly when it leaks instead of racing a ticker.
+	tail, err := NewTail(fname, Config{
+		Timeout:             10,
+		Poll:                true,
+		MaxLineSize:         10,
+		RateLimiter:         rl,
+		skipRateLimiterLeak: true,
+	})
+	assert.Equal(t, err, nil)
+	defer tail.Close()
+
+	writeFile("first\n")
+	first := <-tail.Lines
+	assert.Equal(t, "first", first.Text)
+
+	// The bucket is now full, so this whole logical line (it fits under
+	// MaxLineSize and terminates in the same chunk it started in) is
+	// dropped rather than delivered.
+	writeFile("second\n")
+	time.Sleep(400 * time.Millisecond) // outlast PollInterval so the drop is processed before the bucket is drained below
+
+	rl.mu.Lock()
+	rl.Fill = 0
+	rl.mu.Unlock()
+
+	writeFile("third\n")
+
+	notice := <-tail.Lines
+	if !errors.Is(notice.Err, ErrRateLimited) {
+		t.Fatalf("expected a dropped-lines notice, got %+v", notice)
+	}
+
+	third := <-tail.Lines
+	assert.Equal(t, "third", third.Text)
+}
+
+func TestConfigPoll(t *testing.T) {
+	createFile("")
+	defer removeFile()
+
+	tail, err := NewTail(fname, Config{Timeout: 10, Poll: true})
+	assert.Equal(t, err, nil)
+	defer tail.Close()
+
+	writeFile("foobar\n")
+
+	line := <-tail.Lines
+	assert.Equal(t, "foobar", line.Text)
+}
+
+// countingFileWatcher wraps another FileWatcher, counting calls to
+// BlockUntilExists so TestConfigWatcher can verify openAndWatch actually
+// routes through a custom Config.Watcher rather than falling back to a
+// built-in one.
+type countingFileWatcher struct {
+	FileWatcher
+	blockCalls int32
+}
+
+func (w *countingFileWatcher) BlockUntilExists(ctx context.Context) error {
+	atomic.AddInt32(&w.blockCalls, 1)
+	return w.FileWatcher.BlockUntilExists(ctx)
+}
+
+func TestConfigWatcher(t *testing.T) {
+	defer removeFile()
+
+	watcher := &countingFileWatcher{FileWatcher: newPollingFileWatcher(fname)}
+
+	var tail *Tail
+	var line *Line
+	done := make(chan bool)
+
+	go func() {
+		var err error
+		tail, err = NewTail(fname, Config{Timeout: 10, Watcher: watcher})
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer tail.Close()
+
+		line = <-tail.Lines
+		done <- true
+	}()
+
+	time.Sleep(10 * time.Millisecond) // Allow the listener to fully setup
+	createFile("")
+	writeFile("foobar\n")
+
+	<-done
+
+	assert.Equal(t, "foobar", line.Text)
+	if atomic.LoadInt32(&watcher.blockCalls) == 0 {
+		t.Fatal("custom Config.Watcher's BlockUntilExists was never called")
+	}
+}
+
 func writeContents(f *os.File, contents string) {
 	_, err := f.WriteString(contents)
 	if err != nil {
@@ -0,0 +1,46 @@
+package gotail
+
+import "time"
+
+// SeekInfo records a byte offset within a file, suitable for persisting
+// and later passing back in via Config.Location to resume tailing across
+// restarts.
+type SeekInfo struct {
+	Offset int64
+	Whence int
+}
+
+// Line represents a single line read from the tailed file, along with
+// metadata that lets consumers correlate lines across rotations, resume
+// after a restart, and observe non-fatal errors without the library
+// tearing down the process.
+type Line struct {
+	// Text is the line content with the trailing newline stripped.
+	Text string
+
+	// Num is the 1-indexed line number since the file was last (re)opened.
+	// It resets to 1 whenever the underlying file is replaced, e.g. on
+	// rotation.
+	Num int
+
+	// Time is when the line was read.
+	Time time.Time
+
+	// SeekInfo is the byte offset immediately after this line, so it can
+	// be persisted and fed back in as Config.Location to resume tailing.
+	SeekInfo SeekInfo
+
+	// Err is set when this Line represents a non-fatal read or watch
+	// error rather than real file content. Text is empty in that case.
+	Err error
+
+	// Filename is the path of the file this Line came from. It is only
+	// set on Lines delivered through a MultiTail.
+	Filename string
+
+	// Partial is true when Config.MaxLineSize split a single raw line
+	// across several Lines: Text is one MaxLineSize-sized fragment, not
+	// the full line. All fragments of one line share the same Num; the
+	// final fragment has Partial false.
+	Partial bool
+}
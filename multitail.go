@@ -0,0 +1,318 @@
+package gotail
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+
+	fsnotify "gopkg.in/fsnotify.v1"
+)
+
+// MultiTail tails every file matching any of a set of filepath.Match
+// patterns, merging their lines onto a single channel. Files created
+// after MultiTail starts are picked up automatically, and rotation of an
+// already-matched file (truncate or rename-and-recreate) is handled the
+// same way a single Tail handles it.
+type MultiTail struct {
+	Lines chan *Line
+
+	patterns []string
+	config   Config
+
+	mu    sync.Mutex
+	tails map[string]*Tail
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewMultiTail creates a MultiTail for the given patterns, each suitable
+// for filepath.Match, e.g. "/var/log/app/*.log". It opens every file
+// that already matches, and watches each pattern's parent directory
+// (one watcher per directory, shared across patterns that live in it)
+// for newly created matches.
+func NewMultiTail(patterns []string, config Config) (*MultiTail, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mt := &MultiTail{
+		Lines:    make(chan *Line),
+		patterns: patterns,
+		config:   config,
+		tails:    make(map[string]*Tail),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	if config.RateLimiter != nil {
+		mt.startRateLimiterLeak()
+	}
+
+	for _, dir := range patternDirs(patterns) {
+		if err := mt.watchDir(dir); err != nil {
+			mt.Close()
+			return nil, err
+		}
+	}
+
+	matches, err := mt.matchExisting()
+	if err != nil {
+		mt.Close()
+		return nil, err
+	}
+
+	for _, fname := range matches {
+		mt.addFile(fname)
+	}
+
+	return mt, nil
+}
+
+// Close stops watching and closes every file currently being tailed.
+func (mt *MultiTail) Close() {
+	mt.cancel()
+
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	for _, tail := range mt.tails {
+		if tail != nil {
+			tail.Close()
+		}
+	}
+}
+
+// startRateLimiterLeak leaks one unit from Config.RateLimiter every
+// LeakInterval on behalf of every matched file. Each per-file Tail's own
+// leak goroutine is suppressed (skipRateLimiterLeak, set in addFile) since
+// they'd all be leaking the same shared bucket: one goroutine per matched
+// file would drain it roughly N times faster than LeakInterval intends.
+func (mt *MultiTail) startRateLimiterLeak() {
+	ticker := time.NewTicker(mt.config.RateLimiter.LeakInterval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				mt.config.RateLimiter.leak()
+			case <-mt.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// patternDirs returns the distinct parent directories across patterns,
+// so each is watched only once no matter how many patterns share it.
+func patternDirs(patterns []string) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+
+	for _, pattern := range patterns {
+		dir := filepath.Dir(pattern)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
+
+// matchExisting returns every existing file matching any pattern.
+func (mt *MultiTail) matchExisting() ([]string, error) {
+	seen := make(map[string]bool)
+	var matches []string
+
+	for _, pattern := range mt.patterns {
+		found, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fname := range found {
+			if !seen[fname] {
+				seen[fname] = true
+				matches = append(matches, fname)
+			}
+		}
+	}
+
+	return matches, nil
+}
+
+// matches reports whether fname matches any of mt.patterns.
+func (mt *MultiTail) matches(fname string) bool {
+	for _, pattern := range mt.patterns {
+		if ok, _ := filepath.Match(pattern, fname); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// watchDir watches dir for newly created files and reconciles them
+// against mt.patterns as they appear, using the same fsnotify/polling
+// choice as Config.Poll selects for individual files.
+func (mt *MultiTail) watchDir(dir string) error {
+	if mt.config.Poll {
+		mt.pollDir(dir)
+		return nil
+	}
+
+	return mt.notifyDir(dir)
+}
+
+func (mt *MultiTail) notifyDir(dir string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		for {
+			select {
+			case evt, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if evt.Op&fsnotify.Create == fsnotify.Create && mt.matches(evt.Name) {
+					mt.addFile(evt.Name)
+				}
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			case <-mt.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (mt *MultiTail) pollDir(dir string) {
+	go func() {
+		ticker := time.NewTicker(PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				entries, err := ioutil.ReadDir(dir)
+				if err != nil {
+					continue
+				}
+				for _, entry := range entries {
+					fname := filepath.Join(dir, entry.Name())
+					if mt.matches(fname) {
+						mt.addFile(fname)
+					}
+				}
+			case <-mt.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// addFile starts tailing fname, if it isn't already being tailed, and
+// forwards its lines onto mt.Lines tagged with Filename.
+//
+// Each matched file gets its own Tail, and therefore its own FileWatcher
+// (fsnotify watch or polling ticker). watchDir/pollDir only dedup the
+// per-directory watcher used for *discovering* new files; they don't
+// bound the number of per-file watchers, so fd and goroutine usage still
+// scales with the number of files matching mt.patterns, not with the
+// number of watched directories. Sharing a single fsnotify watcher per
+// directory across the files it contains would require every Tail to
+// pull its change events from a demuxer keyed by filename rather than
+// from its own dedicated FileWatcher, which is a larger rework left for
+// when a caller actually needs to tail directories with very large
+// fan-out.
+func (mt *MultiTail) addFile(fname string) {
+	mt.mu.Lock()
+	if _, exists := mt.tails[fname]; exists {
+		mt.mu.Unlock()
+		return
+	}
+	mt.tails[fname] = nil
+	mt.mu.Unlock()
+
+	// mt.config.RateLimiter, when set, is shared across every matched
+	// file's Tail; skipRateLimiterLeak keeps each of them from also
+	// starting its own leak goroutine for it, since mt.startRateLimiterLeak
+	// already leaks it once on behalf of all of them.
+	config := mt.config
+	if config.RateLimiter != nil {
+		config.skipRateLimiterLeak = true
+	}
+
+	tail, err := NewTail(fname, config)
+	if err != nil {
+		mt.mu.Lock()
+		delete(mt.tails, fname)
+		mt.mu.Unlock()
+		return
+	}
+
+	mt.mu.Lock()
+	// Close may have already iterated and closed every tail in the map
+	// by the time NewTail above returns. Storing tail afterwards would
+	// otherwise leak it: nothing would ever close it, since Close only
+	// runs once. mt.ctx is cancelled first thing in Close, so checking
+	// it here under the same lock Close takes for its iteration is
+	// enough to detect that race and close the straggler ourselves.
+	closed := mt.ctx.Err() != nil
+	if !closed {
+		mt.tails[fname] = tail
+	}
+	mt.mu.Unlock()
+
+	if closed {
+		tail.Close()
+		return
+	}
+
+	go func() {
+		for {
+			select {
+			case line, ok := <-tail.Lines:
+				if !ok {
+					return
+				}
+				line.Filename = fname
+				select {
+				case mt.Lines <- line:
+				case <-mt.ctx.Done():
+					return
+				}
+			case <-tail.Dead():
+				// The file was deleted and never reappeared (or some
+				// other terminal error hit it) rather than Close being
+				// called on mt: tail.Lines is never closed in that case,
+				// so without this case the select above would block
+				// forever and leak both this goroutine and its tails
+				// entry.
+				mt.mu.Lock()
+				delete(mt.tails, fname)
+				mt.mu.Unlock()
+				return
+			case <-mt.ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,149 @@
+package gotail
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestMultiTailRebindsAfterRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotail-multitail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "app.log")
+	if err := ioutil.WriteFile(target, []byte(""), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	mt, err := NewMultiTail([]string{filepath.Join(dir, "*.log")}, Config{Timeout: 10})
+	assert.Equal(t, err, nil)
+	defer mt.Close()
+
+	write := func(contents string) {
+		f, err := os.OpenFile(target, os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(contents); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("before\n")
+
+	line := recvLine(t, mt.Lines)
+	assert.Equal(t, "before", line.Text)
+	assert.Equal(t, target, line.Filename)
+
+	// Rotate: move the old file aside, recreate it fresh, and write to
+	// the new file. The underlying Tail handles this the same way a
+	// standalone Tail handles rename-and-recreate, by reopening target.
+	if err := os.Rename(target, target+".1"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond) // let the watcher observe the removal
+	if err := ioutil.WriteFile(target, []byte(""), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	write("after\n")
+
+	line = recvLine(t, mt.Lines)
+	assert.Equal(t, "after", line.Text)
+	assert.Equal(t, target, line.Filename)
+}
+
+func TestMultiTailSharesOneRateLimiterLeakGoroutine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotail-multitail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a.log", "b.log", "c.log"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(""), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rl := &RateLimiter{Size: 1000, Fill: 100, LeakInterval: 50 * time.Millisecond}
+
+	mt, err := NewMultiTail([]string{filepath.Join(dir, "*.log")}, Config{Timeout: 10, Poll: true, RateLimiter: rl})
+	assert.Equal(t, err, nil)
+	defer mt.Close()
+
+	// Three matched files each starting their own leak goroutine against
+	// the shared rl would drain it ~3x faster than LeakInterval intends.
+	// Give it a handful of intervals and check the fill dropped by close
+	// to one unit per interval, not three.
+	time.Sleep(220 * time.Millisecond)
+
+	rl.mu.Lock()
+	fill := rl.Fill
+	rl.mu.Unlock()
+
+	if fill < 95 {
+		t.Fatalf("RateLimiter leaked too fast: Fill is %d after ~4 intervals, want close to 96 (one matched-file leak goroutine, not three)", fill)
+	}
+}
+
+func TestMultiTailForgetsFileThatNeverReappears(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotail-multitail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "app.log")
+	if err := ioutil.WriteFile(target, []byte(""), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	mt, err := NewMultiTail([]string{filepath.Join(dir, "*.log")}, Config{Timeout: 1, Poll: true})
+	assert.Equal(t, err, nil)
+	defer mt.Close()
+
+	if err := os.Remove(target); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the underlying Tail gives up waiting for target to reappear
+	// and dies terminally, addFile's forwarding goroutine should notice
+	// via Dead() and drop it from mt.tails on its own.
+	deadline := time.After(10 * time.Second)
+	for {
+		mt.mu.Lock()
+		_, tracked := mt.tails[target]
+		mt.mu.Unlock()
+		if !tracked {
+			break
+		}
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("timed out waiting for the deleted file's Tail to be forgotten")
+		}
+	}
+}
+
+// recvLine waits for a Line, failing the test instead of hanging forever
+// if the rotation/reopen this test exercises doesn't complete in time.
+func recvLine(t *testing.T, lines <-chan *Line) *Line {
+	t.Helper()
+
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for a line")
+		return nil
+	}
+}
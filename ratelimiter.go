@@ -0,0 +1,73 @@
+package gotail
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited wraps the error on a synthetic Line emitted when
+// Config.RateLimiter has dropped one or more lines to protect a slow
+// consumer from a burst.
+var ErrRateLimited = errors.New("gotail: rate limited")
+
+// RateLimiter is a leaky bucket used to cap how many lines per
+// LeakInterval are forwarded to Tail.Lines. Size is its capacity and
+// Fill is how full it currently is; both are in units of lines. A
+// RateLimiter is ready to use as soon as Size and LeakInterval are set —
+// Fill starts at zero.
+type RateLimiter struct {
+	Size         int64
+	Fill         int64
+	LeakInterval time.Duration
+
+	mu sync.Mutex
+}
+
+// Pour adds amount to the bucket and reports whether it fit. On false,
+// the bucket is unchanged and the caller should treat amount as
+// rate-limited.
+func (rl *RateLimiter) Pour(amount int64) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.Fill+amount > rl.Size {
+		return false
+	}
+
+	rl.Fill += amount
+	return true
+}
+
+// Wait sleeps for a duration proportional to how full the bucket
+// currently is, so a caller that just got rate limited backs off roughly
+// as long as it'll take the bucket to leak down.
+func (rl *RateLimiter) Wait() {
+	if d := rl.cooldown(); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// cooldown reports how long a caller that just got rate limited should
+// back off, proportional to how full the bucket currently is. It's
+// split out from Wait so a caller that needs to stay responsive to
+// cancellation (e.g. Tail.rateLimiterCooldown) can sleep on its own
+// terms instead of blocking inside RateLimiter.
+func (rl *RateLimiter) cooldown() time.Duration {
+	rl.mu.Lock()
+	fill := rl.Fill
+	rl.mu.Unlock()
+
+	return time.Duration(fill) * rl.LeakInterval
+}
+
+// leak drops the bucket's fill by one unit; it's called by Tail's
+// background leak ticker.
+func (rl *RateLimiter) leak() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.Fill > 0 {
+		rl.Fill--
+	}
+}
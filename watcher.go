@@ -0,0 +1,80 @@
+package gotail
+
+import (
+	"context"
+	"log"
+	"os"
+)
+
+// FileWatcher abstracts how gotail learns that a file it is tailing has
+// changed. Config.Watcher lets callers supply their own implementation;
+// Config.Poll selects the built-in stat-based one. The default wraps
+// fsnotify.
+//
+// A FileWatcher is only ever used to watch one file at a time.
+type FileWatcher interface {
+	// BlockUntilExists blocks until the watched file comes into being,
+	// or ctx is cancelled.
+	BlockUntilExists(ctx context.Context) error
+
+	// ChangeEvents starts watching the file described by fi (as
+	// returned by the os.File currently open on it) and returns a
+	// FileChanges that reports modifications, truncations, and
+	// deletion. Delivery stops once ctx is cancelled.
+	ChangeEvents(ctx context.Context, fi os.FileInfo) (*FileChanges, error)
+}
+
+// FileChanges is how a FileWatcher reports what happened to a file.
+// Exactly one of the channels fires per underlying event.
+type FileChanges struct {
+	Modified  chan bool
+	Truncated chan bool
+	Deleted   chan bool
+}
+
+func newFileChanges() *FileChanges {
+	return &FileChanges{
+		Modified:  make(chan bool),
+		Truncated: make(chan bool),
+		Deleted:   make(chan bool),
+	}
+}
+
+// notifyModified reports a modification, giving up if ctx is cancelled
+// first. Without the ctx.Done case, a FileWatcher racing Close (or a
+// cancelled parent context) against an in-flight event would otherwise
+// block forever on an unbuffered send nobody is left to receive.
+func (fc *FileChanges) notifyModified(ctx context.Context) {
+	select {
+	case fc.Modified <- true:
+	case <-ctx.Done():
+	}
+}
+
+func (fc *FileChanges) notifyTruncated(ctx context.Context) {
+	select {
+	case fc.Truncated <- true:
+	case <-ctx.Done():
+	}
+}
+
+func (fc *FileChanges) notifyDeleted(ctx context.Context) {
+	select {
+	case fc.Deleted <- true:
+	case <-ctx.Done():
+	}
+}
+
+// newFileWatcher picks the FileWatcher implementation for fname
+// according to config: an explicit Config.Watcher wins, otherwise
+// Config.Poll selects the polling backend, and fsnotify is the default.
+// logger receives the new watcher's own diagnostics.
+func newFileWatcher(fname string, config Config, logger *log.Logger) FileWatcher {
+	if config.Watcher != nil {
+		return config.Watcher
+	}
+	if config.Poll {
+		return newPollingFileWatcher(fname)
+	}
+	return newFsnotifyFileWatcher(fname, logger)
+}
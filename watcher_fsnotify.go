@@ -0,0 +1,96 @@
+package gotail
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	fsnotify "gopkg.in/fsnotify.v1"
+)
+
+// existsPollInterval is how often fsnotifyFileWatcher.BlockUntilExists
+// re-checks for a file, since fsnotify cannot watch a path that doesn't
+// exist yet.
+const existsPollInterval = 100 * time.Millisecond
+
+// fsnotifyFileWatcher is the default FileWatcher. It is a thin wrapper
+// around the fsnotify-based watching gotail has always done.
+type fsnotifyFileWatcher struct {
+	fname string
+	log   *log.Logger
+}
+
+func newFsnotifyFileWatcher(fname string, logger *log.Logger) *fsnotifyFileWatcher {
+	return &fsnotifyFileWatcher{fname: fname, log: logger}
+}
+
+func (w *fsnotifyFileWatcher) BlockUntilExists(ctx context.Context) error {
+	for {
+		if _, err := os.Stat(w.fname); err == nil {
+			return nil
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(existsPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (w *fsnotifyFileWatcher) ChangeEvents(ctx context.Context, fi os.FileInfo) (*FileChanges, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := watcher.Add(w.fname); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	changes := newFileChanges()
+	size := fi.Size()
+
+	go func() {
+		defer watcher.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if evt.Op&(fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+					changes.notifyDeleted(ctx)
+					return
+				}
+				if evt.Op&fsnotify.Write == fsnotify.Write {
+					if newFi, err := os.Stat(w.fname); err == nil {
+						if newFi.Size() < size {
+							size = newFi.Size()
+							changes.notifyTruncated(ctx)
+							continue
+						}
+						size = newFi.Size()
+					}
+					changes.notifyModified(ctx)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				if err != nil {
+					w.log.Println("[WARN] watcher error: ", err)
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
@@ -0,0 +1,88 @@
+package gotail
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// PollInterval is how often the polling FileWatcher re-stats the file it
+// is watching. It's a package var rather than a Config field so tests can
+// shrink it; production callers should rarely need to touch it.
+var PollInterval = 250 * time.Millisecond
+
+// pollingFileWatcher is a FileWatcher backed by periodic os.Stat calls
+// rather than OS-level file-change notifications. Select it with
+// Config.Poll for filesystems where inotify is unreliable or unavailable,
+// e.g. NFS, FUSE, SMB, and some container bind mounts.
+type pollingFileWatcher struct {
+	fname string
+}
+
+func newPollingFileWatcher(fname string) *pollingFileWatcher {
+	return &pollingFileWatcher{fname: fname}
+}
+
+func (w *pollingFileWatcher) BlockUntilExists(ctx context.Context) error {
+	for {
+		if _, err := os.Stat(w.fname); err == nil {
+			return nil
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(PollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (w *pollingFileWatcher) ChangeEvents(ctx context.Context, fi os.FileInfo) (*FileChanges, error) {
+	changes := newFileChanges()
+
+	go func() {
+		size := fi.Size()
+		modTime := fi.ModTime()
+		ino, hasIno := fileID(fi)
+
+		ticker := time.NewTicker(PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			newFi, err := os.Stat(w.fname)
+			if err != nil {
+				if os.IsNotExist(err) {
+					changes.notifyDeleted(ctx)
+					return
+				}
+				continue
+			}
+
+			if newIno, ok := fileID(newFi); hasIno && ok && newIno != ino {
+				changes.notifyDeleted(ctx)
+				return
+			}
+
+			if newFi.Size() < size {
+				size, modTime = newFi.Size(), newFi.ModTime()
+				changes.notifyTruncated(ctx)
+				continue
+			}
+
+			if newFi.Size() != size || !newFi.ModTime().Equal(modTime) {
+				size, modTime = newFi.Size(), newFi.ModTime()
+				changes.notifyModified(ctx)
+			}
+		}
+	}()
+
+	return changes, nil
+}